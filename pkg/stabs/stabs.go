@@ -0,0 +1,389 @@
+// Package stabs decodes the classic Sun/Apple STABS debugging symbols
+// (the N_SO/N_OSO/N_FUN/N_SLINE/... entries described in types.NType's
+// N_STAB constants) into a structured tree of compilation units, object
+// files, functions and source lines.
+//
+// Mach-Os built with older toolchains (and most kexts) ship stabs
+// instead of DWARF, so this is the only way to recover source-location
+// information for them.
+package stabs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// A SourceLine is a single N_SLINE sample mapping an address to a line
+// number within the enclosing function.
+type SourceLine struct {
+	Line    int
+	Address uint64
+}
+
+// A VarKind distinguishes the three kinds of variable stabs that can
+// appear inside a Scope.
+type VarKind uint8
+
+const (
+	VarLocal    VarKind = iota // N_LSYM
+	VarParam                   // N_PSYM
+	VarRegister                // N_RSYM
+)
+
+// A Variable is a local (N_LSYM), parameter (N_PSYM) or register
+// (N_RSYM) symbol found inside a lexical scope.
+type Variable struct {
+	Name   string
+	Type   types.NType
+	Offset int32
+	Kind   VarKind
+}
+
+// A HeaderInclude records an N_BINCL/N_EINCL bracketed header, or a
+// standalone N_EXCL referencing one already seen elsewhere with a
+// matching checksum.
+type HeaderInclude struct {
+	Name     string
+	Checksum uint32
+	Excluded bool // from a standalone N_EXCL rather than a N_BINCL/N_EINCL pair
+}
+
+// A Scope is a lexical block delimited by an N_LBRAC/N_RBRAC pair.
+type Scope struct {
+	Start     uint64
+	End       uint64
+	Variables []Variable
+	Scopes    []*Scope
+}
+
+// A Function is the set of stabs bracketed by N_BNSYM/N_ENSYM (or, for
+// older toolchains, a bare N_FUN followed by N_SLINE records).
+type Function struct {
+	Name    string
+	Address uint64
+	End     uint64
+	Lines   []SourceLine
+	Scope   *Scope
+}
+
+// An ObjectFile is the `.o` referenced by an N_OSO entry.
+type ObjectFile struct {
+	Name       string
+	ModTime    uint32
+	CPUSubtype uint8
+	Functions  []*Function
+	Includes   []HeaderInclude
+}
+
+// A CompilationUnit is the source file named by an N_SO pair.
+type CompilationUnit struct {
+	Directory string
+	Name      string
+	Objects   []*ObjectFile
+}
+
+// Decoder turns a stream of Nlist64 stabs into a tree of
+// CompilationUnits and builds an address index for Symbolicate.
+type Decoder struct {
+	units []*CompilationUnit
+
+	index   []addrFunc // sorted by address for Symbolicate
+	indexed bool
+}
+
+type addrFunc struct {
+	addr   uint64
+	cu     *CompilationUnit
+	obj    *ObjectFile
+	fn     *Function
+	lineNo int // index into fn.Lines of the line active at addr
+}
+
+func name(sym types.Nlist64, strtab []byte) string {
+	if int(sym.Name) >= len(strtab) {
+		return ""
+	}
+	end := bytes.IndexByte(strtab[sym.Name:], 0)
+	if end < 0 {
+		return string(strtab[sym.Name:])
+	}
+	return string(strtab[sym.Name : int(sym.Name)+end])
+}
+
+// decode consumes syms (only entries for which Type.IsDebugSym() is
+// true are considered) and the associated string table, and returns the
+// decoded compilation units.
+func decode(syms []types.Nlist64, strtab []byte) []*CompilationUnit {
+	var units []*CompilationUnit
+
+	var cu *CompilationUnit
+	var obj *ObjectFile
+	var fn *Function
+	var scopeStack []*Scope
+	var includeStack []HeaderInclude
+
+	// pendingDir holds the directory half of a dir+file N_SO pair until
+	// the matching file-name N_SO arrives.
+	var pendingDir string
+
+	for i := 0; i < len(syms); i++ {
+		sym := syms[i]
+		if !sym.Type.IsDebugSym() {
+			continue
+		}
+
+		switch uint8(sym.Type) {
+		case types.N_SO:
+			n := name(sym, strtab)
+			switch {
+			case n == "":
+				// N_SO with an empty name closes the current compilation unit.
+				cu = nil
+				obj = nil
+				pendingDir = ""
+			case pendingDir != "":
+				// The file-name half of a dir+file pair.
+				cu = &CompilationUnit{Directory: pendingDir, Name: n}
+				units = append(units, cu)
+				pendingDir = ""
+			case len(n) > 0 && n[len(n)-1] == '/':
+				// The directory half of a dir+file pair; wait for the
+				// file-name N_SO that completes it.
+				pendingDir = n
+			default:
+				// A standalone N_SO with the full path and no separate
+				// directory entry.
+				cu = &CompilationUnit{Name: n}
+				units = append(units, cu)
+			}
+		case types.N_OSO:
+			obj = &ObjectFile{
+				Name:       name(sym, strtab),
+				ModTime:    uint32(sym.Value),
+				CPUSubtype: sym.Sect,
+			}
+			if cu == nil {
+				cu = &CompilationUnit{}
+				units = append(units, cu)
+			}
+			cu.Objects = append(cu.Objects, obj)
+		case types.N_BNSYM:
+			fn = &Function{Address: sym.Value}
+		case types.N_FUN:
+			if n := name(sym, strtab); n != "" {
+				if fn == nil {
+					fn = &Function{Address: sym.Value}
+				}
+				fn.Name = n
+				fn.Address = sym.Value
+			} else if fn != nil {
+				// A nameless N_FUN closes the function. Toolchains that
+				// don't bracket functions with N_BNSYM/N_ENSYM use this
+				// as the only way to terminate one, so flush it here.
+				fn.End = sym.Value
+				if obj != nil {
+					obj.Functions = append(obj.Functions, fn)
+				}
+				fn = nil
+			}
+		case types.N_SLINE:
+			if fn != nil {
+				fn.Lines = append(fn.Lines, SourceLine{
+					Line:    int(sym.Desc),
+					Address: sym.Value,
+				})
+			}
+		case types.N_ENSYM:
+			if fn != nil {
+				if obj != nil {
+					obj.Functions = append(obj.Functions, fn)
+				}
+				fn = nil
+			}
+		case types.N_LBRAC:
+			s := &Scope{Start: sym.Value}
+			if n := len(scopeStack); n > 0 {
+				scopeStack[n-1].Scopes = append(scopeStack[n-1].Scopes, s)
+			} else if fn != nil && fn.Scope == nil {
+				fn.Scope = s
+			}
+			scopeStack = append(scopeStack, s)
+		case types.N_RBRAC:
+			if n := len(scopeStack); n > 0 {
+				scopeStack[n-1].End = sym.Value
+				scopeStack = scopeStack[:n-1]
+			}
+		case types.N_LSYM, types.N_PSYM, types.N_RSYM:
+			kind := VarLocal
+			switch uint8(sym.Type) {
+			case types.N_PSYM:
+				kind = VarParam
+			case types.N_RSYM:
+				kind = VarRegister
+			}
+			v := Variable{
+				Name:   name(sym, strtab),
+				Type:   sym.Type,
+				Offset: int32(sym.Value),
+				Kind:   kind,
+			}
+			if n := len(scopeStack); n > 0 {
+				scopeStack[n-1].Variables = append(scopeStack[n-1].Variables, v)
+			} else if fn != nil {
+				if fn.Scope == nil {
+					fn.Scope = &Scope{Start: fn.Address}
+				}
+				fn.Scope.Variables = append(fn.Scope.Variables, v)
+			}
+		case types.N_BINCL:
+			includeStack = append(includeStack, HeaderInclude{
+				Name:     name(sym, strtab),
+				Checksum: uint32(sym.Value),
+			})
+		case types.N_EINCL:
+			if n := len(includeStack); n > 0 {
+				inc := includeStack[n-1]
+				includeStack = includeStack[:n-1]
+				if obj != nil {
+					obj.Includes = append(obj.Includes, inc)
+				}
+			}
+		case types.N_EXCL:
+			if obj != nil {
+				obj.Includes = append(obj.Includes, HeaderInclude{
+					Name:     name(sym, strtab),
+					Checksum: uint32(sym.Value),
+					Excluded: true,
+				})
+			}
+		}
+	}
+
+	return units
+}
+
+// NewDecoder decodes syms/strtab and returns a Decoder ready to be
+// queried with Symbolicate and Iterate.
+func NewDecoder(syms []types.Nlist64, strtab []byte) *Decoder {
+	return &Decoder{units: decode(syms, strtab)}
+}
+
+// Units returns the decoded compilation units.
+func (d *Decoder) Units() []*CompilationUnit {
+	return d.units
+}
+
+func (d *Decoder) buildIndex() {
+	if d.indexed {
+		return
+	}
+	for _, cu := range d.units {
+		for _, obj := range cu.Objects {
+			for _, fn := range obj.Functions {
+				for i := range fn.Lines {
+					d.index = append(d.index, addrFunc{
+						addr:   fn.Lines[i].Address,
+						cu:     cu,
+						obj:    obj,
+						fn:     fn,
+						lineNo: i,
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(d.index, func(i, j int) bool { return d.index[i].addr < d.index[j].addr })
+	d.indexed = true
+}
+
+// Symbolicate resolves addr to the source file, function name and line
+// number of the N_SLINE sample at or immediately before it.
+func (d *Decoder) Symbolicate(addr uint64) (file string, function string, line int, ok bool) {
+	d.buildIndex()
+
+	if len(d.index) == 0 {
+		return "", "", 0, false
+	}
+
+	i := sort.Search(len(d.index), func(i int) bool { return d.index[i].addr > addr })
+	if i == 0 {
+		return "", "", 0, false
+	}
+	e := d.index[i-1]
+
+	return e.cu.Name, e.fn.Name, e.fn.Lines[e.lineNo].Line, true
+}
+
+// Visitor is called by Iterate for every decoded record, outermost
+// first. v is one of *CompilationUnit, *ObjectFile, HeaderInclude,
+// *Function, SourceLine, *Scope or Variable.
+type Visitor func(v interface{}) error
+
+// Iterate walks the decoded tree depth-first, calling visit for every
+// compilation unit, object file, function, source line, scope and
+// variable.
+func (d *Decoder) Iterate(visit Visitor) error {
+	err := d.iterate(visit)
+	if err == ErrStopIteration {
+		return nil
+	}
+	return err
+}
+
+func (d *Decoder) iterate(visit Visitor) error {
+	for _, cu := range d.units {
+		if err := visit(cu); err != nil {
+			return err
+		}
+		for _, obj := range cu.Objects {
+			if err := visit(obj); err != nil {
+				return err
+			}
+			for _, inc := range obj.Includes {
+				if err := visit(inc); err != nil {
+					return err
+				}
+			}
+			for _, fn := range obj.Functions {
+				if err := visit(fn); err != nil {
+					return err
+				}
+				for _, l := range fn.Lines {
+					if err := visit(l); err != nil {
+						return err
+					}
+				}
+				if fn.Scope != nil {
+					if err := iterateScope(fn.Scope, visit); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func iterateScope(s *Scope, visit Visitor) error {
+	if err := visit(s); err != nil {
+		return err
+	}
+	for _, v := range s.Variables {
+		if err := visit(v); err != nil {
+			return err
+		}
+	}
+	for _, child := range s.Scopes {
+		if err := iterateScope(child, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrStopIteration is a sentinel error a Visitor can return to stop
+// Iterate early without propagating a real error.
+var ErrStopIteration = fmt.Errorf("stop iteration")
@@ -0,0 +1,107 @@
+package stabs
+
+import (
+	"testing"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+func strtabWithNames(names ...string) ([]byte, map[string]uint32) {
+	buf := []byte{0}
+	offsets := make(map[string]uint32, len(names))
+	for _, n := range names {
+		offsets[n] = uint32(len(buf))
+		buf = append(buf, []byte(n)...)
+		buf = append(buf, 0)
+	}
+	return buf, offsets
+}
+
+func TestDecodeSODirFilePairing(t *testing.T) {
+	strtab, off := strtabWithNames("/usr/src/", "foo.c")
+
+	syms := []types.Nlist64{
+		{Nlist: types.Nlist{Name: off["/usr/src/"], Type: types.N_SO}},
+		{Nlist: types.Nlist{Name: off["foo.c"], Type: types.N_SO}},
+	}
+
+	units := decode(syms, strtab)
+	if len(units) != 1 {
+		t.Fatalf("got %d compilation units, want 1", len(units))
+	}
+	if units[0].Directory != "/usr/src/" || units[0].Name != "foo.c" {
+		t.Fatalf("got {Directory:%q Name:%q}, want {Directory:\"/usr/src/\" Name:\"foo.c\"}",
+			units[0].Directory, units[0].Name)
+	}
+}
+
+func TestDecodeSOStandaloneFullPath(t *testing.T) {
+	strtab, off := strtabWithNames("/usr/src/foo.c")
+
+	syms := []types.Nlist64{
+		{Nlist: types.Nlist{Name: off["/usr/src/foo.c"], Type: types.N_SO}},
+	}
+
+	units := decode(syms, strtab)
+	if len(units) != 1 {
+		t.Fatalf("got %d compilation units, want 1", len(units))
+	}
+	if units[0].Directory != "" || units[0].Name != "/usr/src/foo.c" {
+		t.Fatalf("got {Directory:%q Name:%q}, want {Directory:\"\" Name:\"/usr/src/foo.c\"}",
+			units[0].Directory, units[0].Name)
+	}
+}
+
+func TestDecodeBareFunctionWithoutBracket(t *testing.T) {
+	strtab, off := strtabWithNames("foo.c", "_foo")
+
+	syms := []types.Nlist64{
+		{Nlist: types.Nlist{Name: off["foo.c"], Type: types.N_SO}},
+		{Nlist: types.Nlist{Type: types.N_OSO}},
+		{Nlist: types.Nlist{Name: off["_foo"], Type: types.N_FUN}, Value: 0x1000},
+		{Nlist: types.Nlist{Type: types.N_SLINE, Desc: 42}, Value: 0x1000},
+		{Nlist: types.Nlist{Type: types.N_FUN}, Value: 0x1010},
+	}
+
+	units := decode(syms, strtab)
+	if len(units) != 1 || len(units[0].Objects) != 1 {
+		t.Fatalf("got %d units, want 1 with 1 object", len(units))
+	}
+
+	obj := units[0].Objects[0]
+	if len(obj.Functions) != 1 {
+		t.Fatalf("got %d functions, want 1 (bare N_FUN close was dropped)", len(obj.Functions))
+	}
+
+	fn := obj.Functions[0]
+	if fn.Name != "_foo" || fn.Address != 0x1000 || fn.End != 0x1010 {
+		t.Fatalf("got %+v, want Name:_foo Address:0x1000 End:0x1010", fn)
+	}
+	if len(fn.Lines) != 1 || fn.Lines[0].Line != 42 {
+		t.Fatalf("got Lines:%+v, want one line with Line:42", fn.Lines)
+	}
+}
+
+func TestDecodeHeaderIncludes(t *testing.T) {
+	strtab, off := strtabWithNames("foo.c", "foo.h", "bar.h")
+
+	syms := []types.Nlist64{
+		{Nlist: types.Nlist{Name: off["foo.c"], Type: types.N_SO}},
+		{Nlist: types.Nlist{Type: types.N_OSO}},
+		{Nlist: types.Nlist{Name: off["foo.h"], Type: types.N_BINCL}, Value: 0xabc},
+		{Nlist: types.Nlist{Type: types.N_EINCL}},
+		{Nlist: types.Nlist{Name: off["bar.h"], Type: types.N_EXCL}, Value: 0xdef},
+	}
+
+	units := decode(syms, strtab)
+	obj := units[0].Objects[0]
+	if len(obj.Includes) != 2 {
+		t.Fatalf("got %d includes, want 2", len(obj.Includes))
+	}
+	if got := obj.Includes[0]; got.Name != "foo.h" || got.Checksum != 0xabc || got.Excluded {
+		t.Fatalf("got %+v, want Name:foo.h Checksum:0xabc Excluded:false", got)
+	}
+	if got := obj.Includes[1]; got.Name != "bar.h" || got.Checksum != 0xdef || !got.Excluded {
+		t.Fatalf("got %+v, want Name:bar.h Checksum:0xdef Excluded:true", got)
+	}
+}
@@ -0,0 +1,232 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// putUleb128 appends the ULEB128 encoding of v to b, the inverse of
+// ReadUleb128.
+func putUleb128(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			break
+		}
+	}
+	return b
+}
+
+// A Writer accumulates TrieEntrys and encodes them into the compressed
+// dyld export-trie format consumed by ParseTrie/WalkTrie.
+type Writer struct {
+	loadAddress uint64
+	entries     []TrieEntry
+}
+
+// NewWriter creates a Writer that will encode addresses relative to
+// loadAddress, matching the loadAddress a caller later passes to
+// ParseTrie.
+func NewWriter(loadAddress uint64) *Writer {
+	return &Writer{loadAddress: loadAddress}
+}
+
+// Add queues entries to be written by Bytes.
+func (w *Writer) Add(entries ...TrieEntry) {
+	w.entries = append(w.entries, entries...)
+}
+
+// Bytes encodes the queued entries into a trie.
+func (w *Writer) Bytes() ([]byte, error) {
+	return Build(w.entries, w.loadAddress)
+}
+
+// Build produces the compressed dyld export-trie byte format that
+// ParseTrie and WalkTrie consume, so the result can be written back out
+// as an LC_DYLD_EXPORTS_TRIE / LC_DYLD_INFO export blob. Addresses are
+// encoded relative to loadAddress, mirroring how ParseTrie adds
+// loadAddress back on the way in.
+func Build(entries []TrieEntry, loadAddress uint64) ([]byte, error) {
+	sorted := make([]TrieEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Name == sorted[i-1].Name {
+			return nil, fmt.Errorf("trie: duplicate symbol name %q", sorted[i].Name)
+		}
+	}
+
+	root, err := buildNode(sorted, 0)
+	if err != nil {
+		return nil, err
+	}
+	nodes := flattenNodes(root)
+
+	// Resolve child offsets with the standard fixed-point loop: assign
+	// tentative offsets, re-encode every node's ULEB128 child pointers
+	// against them, and repeat until no node's encoded size changes.
+	// Sizes are monotonically non-decreasing (a larger offset never
+	// needs fewer ULEB128 bytes), so this always converges.
+	for {
+		changed := false
+		var offset uint64
+		for _, n := range nodes {
+			if n.offset != offset {
+				n.offset = offset
+				changed = true
+			}
+			offset += uint64(len(n.encode(loadAddress)))
+		}
+		if !changed {
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		buf.Write(n.encode(loadAddress))
+	}
+
+	return buf.Bytes(), nil
+}
+
+type trieBuildEdge struct {
+	label []byte
+	child *trieBuildNode
+}
+
+type trieBuildNode struct {
+	terminal *TrieEntry
+	edges    []trieBuildEdge
+	offset   uint64
+}
+
+// buildNode partitions entries (all of which already share the prefix
+// entries[0].Name[:prefixLen]) into a node, merging entries that share
+// a further common prefix into a single child edge.
+func buildNode(entries []TrieEntry, prefixLen int) (*trieBuildNode, error) {
+	n := &trieBuildNode{}
+
+	for i := 0; i < len(entries); {
+		if len(entries[i].Name) == prefixLen {
+			e := entries[i]
+			n.terminal = &e
+			i++
+			continue
+		}
+
+		c := entries[i].Name[prefixLen]
+		j := i + 1
+		for j < len(entries) && len(entries[j].Name) > prefixLen && entries[j].Name[prefixLen] == c {
+			j++
+		}
+
+		group := entries[i:j]
+		common := commonPrefixLen(group, prefixLen)
+		child, err := buildNode(group, prefixLen+common)
+		if err != nil {
+			return nil, err
+		}
+
+		n.edges = append(n.edges, trieBuildEdge{
+			label: []byte(group[0].Name[prefixLen : prefixLen+common]),
+			child: child,
+		})
+		i = j
+	}
+
+	if len(n.edges) > 0xff {
+		return nil, fmt.Errorf("trie: node has %d children, format only supports 255", len(n.edges))
+	}
+
+	return n, nil
+}
+
+// commonPrefixLen returns how many bytes past start are shared by every
+// name in entries (entries is assumed already grouped by a common byte
+// at start, so the result is always at least 1).
+func commonPrefixLen(entries []TrieEntry, start int) int {
+	first := entries[0].Name
+	length := len(first) - start
+
+	for _, e := range entries[1:] {
+		l := 0
+		for start+l < len(e.Name) && start+l < len(first) && e.Name[start+l] == first[start+l] {
+			l++
+		}
+		if l < length {
+			length = l
+		}
+	}
+
+	return length
+}
+
+func flattenNodes(root *trieBuildNode) []*trieBuildNode {
+	var nodes []*trieBuildNode
+	var walk func(n *trieBuildNode)
+	walk = func(n *trieBuildNode) {
+		nodes = append(nodes, n)
+		for _, e := range n.edges {
+			walk(e.child)
+		}
+	}
+	walk(root)
+	return nodes
+}
+
+// terminalPayload encodes this node's terminal record (everything after
+// the leading ULEB128 terminalSize), or nil if the node isn't terminal.
+func (n *trieBuildNode) terminalPayload(loadAddress uint64) []byte {
+	if n.terminal == nil {
+		return nil
+	}
+
+	e := n.terminal
+
+	var b []byte
+	b = putUleb128(b, uint64(e.Flags))
+
+	if e.Flags.ReExport() {
+		b = putUleb128(b, e.Other)
+		b = append(b, []byte(e.FoundInDylib)...)
+		b = append(b, 0)
+		return b
+	}
+
+	addr := e.Address
+	if e.Flags.Regular() || e.Flags.ThreadLocal() {
+		addr -= loadAddress
+	}
+	b = putUleb128(b, addr)
+
+	if e.Flags.StubAndResolver() {
+		b = putUleb128(b, e.Other)
+	}
+
+	return b
+}
+
+func (n *trieBuildNode) encode(loadAddress uint64) []byte {
+	payload := n.terminalPayload(loadAddress)
+
+	var b []byte
+	b = putUleb128(b, uint64(len(payload)))
+	b = append(b, payload...)
+
+	b = append(b, byte(len(n.edges)))
+	for _, e := range n.edges {
+		b = append(b, e.label...)
+		b = append(b, 0)
+		b = putUleb128(b, e.child.offset)
+	}
+
+	return b
+}
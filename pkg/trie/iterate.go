@@ -0,0 +1,269 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/blacktop/go-macho/types"
+)
+
+// ErrStopIteration is a sentinel a visit callback can return from
+// Iterate/IteratePrefix to stop the walk early without it being
+// reported back to the caller as a failure.
+var ErrStopIteration = errors.New("trie: iteration stopped")
+
+// Iterate decodes the same entries as ParseTrie with a depth-first walk
+// but yields each terminal to visit as soon as it is decoded, instead of
+// allocating a []TrieEntry up front. Children are visited in the order
+// their edges are stored, so entries are not necessarily yielded in the
+// order ParseTrie returns them. This matters for the dyld shared
+// cache, where a single image can export hundreds of thousands of
+// symbols and callers usually only want a subset.
+func Iterate(data []byte, loadAddress uint64, visit func(TrieEntry) error) error {
+	it := &trieIterator{r: bytes.NewReader(data), scratch: make([]byte, 0, 256), visit: visit}
+	err := it.walk(0, loadAddress)
+	if err == ErrStopIteration {
+		return nil
+	}
+	return err
+}
+
+// IteratePrefix is like Iterate, but prunes the traversal to the
+// subtree under prefix: it descends only into the single edge whose
+// label shares a prefix with the query (exactly like WalkTrie does for
+// exact matches), then continues into the whole subtree once prefix
+// has been fully consumed.
+func IteratePrefix(data []byte, prefix string, loadAddress uint64, visit func(TrieEntry) error) error {
+	it := &trieIterator{r: bytes.NewReader(data), scratch: make([]byte, 0, 256), visit: visit}
+	err := it.walkPrefix(0, prefix, loadAddress)
+	if err == ErrStopIteration {
+		return nil
+	}
+	return err
+}
+
+// trieIterator holds the mutable state shared across a walk: the
+// reader and a single reusable scratch buffer that accumulates the
+// name along the current root-to-node path, growing on descent and
+// truncating back on backtrack instead of allocating per node.
+type trieIterator struct {
+	r       *bytes.Reader
+	scratch []byte
+	visit   func(TrieEntry) error
+}
+
+func (it *trieIterator) walk(offset uint64, loadAddress uint64) error {
+	r := it.r
+
+	if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+		return err
+	}
+
+	terminalSize, err := ReadUleb128(r)
+	if err != nil {
+		return err
+	}
+
+	if terminalSize != 0 {
+		entry, err := it.readTerminal(loadAddress)
+		if err != nil {
+			return err
+		}
+		if err := it.visit(entry); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.Seek(int64(offset+terminalSize+1), io.SeekStart); err != nil {
+		return err
+	}
+
+	childrenRemaining, err := r.ReadByte()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(childrenRemaining); i++ {
+		savedLen := len(it.scratch)
+
+		for {
+			c, err := r.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if c == '\x00' {
+				break
+			}
+			it.scratch = append(it.scratch, c)
+		}
+
+		childOffset, err := ReadUleb128(r)
+		if err != nil {
+			return err
+		}
+
+		resume, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		if err := it.walk(childOffset, loadAddress); err != nil {
+			it.scratch = it.scratch[:savedLen]
+			return err
+		}
+
+		it.scratch = it.scratch[:savedLen]
+		if _, err := r.Seek(resume, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (it *trieIterator) walkPrefix(offset uint64, prefix string, loadAddress uint64) error {
+	r := it.r
+
+	if len(prefix) == 0 {
+		return it.walk(offset, loadAddress)
+	}
+
+	if _, err := r.Seek(int64(offset), io.SeekStart); err != nil {
+		return err
+	}
+
+	terminalSize, err := ReadUleb128(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.Seek(int64(offset+terminalSize+1), io.SeekStart); err != nil {
+		return err
+	}
+
+	childrenRemaining, err := r.ReadByte()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < int(childrenRemaining); i++ {
+		var label []byte
+		for {
+			c, err := r.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if c == '\x00' {
+				break
+			}
+			label = append(label, c)
+		}
+
+		childOffset, err := ReadUleb128(r)
+		if err != nil {
+			return err
+		}
+
+		overlap := len(label)
+		if len(prefix) < overlap {
+			overlap = len(prefix)
+		}
+		if !bytes.Equal(label[:overlap], []byte(prefix[:overlap])) {
+			// Wrong edge: its label diverges from the query, try the
+			// next child.
+			continue
+		}
+
+		// The trie's edges at a node always start with distinct bytes,
+		// so at most one child can match the query — we're done either
+		// way once we've descended into it.
+		savedLen := len(it.scratch)
+		it.scratch = append(it.scratch, label...)
+
+		var err2 error
+		if len(prefix) <= len(label) {
+			err2 = it.walk(childOffset, loadAddress)
+		} else {
+			err2 = it.walkPrefix(childOffset, prefix[len(label):], loadAddress)
+		}
+
+		it.scratch = it.scratch[:savedLen]
+		return err2
+	}
+
+	return nil
+}
+
+func (it *trieIterator) readTerminal(loadAddress uint64) (TrieEntry, error) {
+	r := it.r
+
+	symFlagInt, err := ReadUleb128(r)
+	if err != nil {
+		return TrieEntry{}, err
+	}
+	flags := types.ExportFlag(symFlagInt)
+
+	var symOtherInt uint64
+	var reExportSymBytes []byte
+
+	if flags.ReExport() {
+		symOtherInt, err = ReadUleb128(r)
+		if err != nil {
+			return TrieEntry{}, err
+		}
+		for {
+			s, err := r.ReadByte()
+			if err == io.EOF {
+				break
+			}
+			if s == '\x00' {
+				break
+			}
+			reExportSymBytes = append(reExportSymBytes, s)
+		}
+	}
+
+	var symValueInt uint64
+	if !flags.ReExport() {
+		symValueInt, err = ReadUleb128(r)
+		if err != nil {
+			return TrieEntry{}, err
+		}
+
+		if flags.StubAndResolver() {
+			symOtherInt, err = ReadUleb128(r)
+			if err != nil {
+				return TrieEntry{}, err
+			}
+		}
+
+		if flags.Regular() || flags.ThreadLocal() {
+			symValueInt += loadAddress
+		}
+	}
+
+	name := string(it.scratch)
+	if len(reExportSymBytes) > 0 {
+		name = name + " (" + string(reExportSymBytes) + ")"
+	}
+
+	return TrieEntry{
+		Name:    name,
+		Flags:   flags,
+		Other:   symOtherInt,
+		Address: symValueInt,
+	}, nil
+}
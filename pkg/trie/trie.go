@@ -144,22 +144,24 @@ func ParseTrie(trieData []byte, loadAddress uint64) ([]TrieEntry, error) {
 				}
 			}
 
-			symValueInt, err = ReadUleb128(r)
-			if err != nil {
-				return nil, err
-			}
-
-			if flags.StubAndResolver() {
-				symOtherInt, err = ReadUleb128(r)
+			if !flags.ReExport() {
+				symValueInt, err = ReadUleb128(r)
 				if err != nil {
 					return nil, err
 				}
-				// TODO: handle stubs
-				// log.Debugf("StubAndResolver: %d", symOtherInt)
-			}
 
-			if flags.Regular() || flags.ThreadLocal() {
-				symValueInt += loadAddress
+				if flags.StubAndResolver() {
+					symOtherInt, err = ReadUleb128(r)
+					if err != nil {
+						return nil, err
+					}
+					// TODO: handle stubs
+					// log.Debugf("StubAndResolver: %d", symOtherInt)
+				}
+
+				if flags.Regular() || flags.ThreadLocal() {
+					symValueInt += loadAddress
+				}
 			}
 
 			if len(reExportSymBytes) > 0 {
@@ -169,28 +169,148 @@ func (d NDescType) GetLibraryOrdinal() NDescType {
 	return (d >> 8) & 0xff
 }
 
+// SetLibraryOrdinal returns d with its library-ordinal byte (the
+// inverse of GetLibraryOrdinal) replaced by ordinal.
+func (d NDescType) SetLibraryOrdinal(ordinal NDescType) NDescType {
+	return (d & 0x00ff) | ((ordinal & 0xff) << 8)
+}
+
+// SetWeakRef returns d with the N_WEAK_REF bit set.
+func (d NDescType) SetWeakRef() NDescType {
+	return d | WEAK_REF
+}
+
+// SetWeakDef returns d with the N_WEAK_DEF bit set.
+func (d NDescType) SetWeakDef() NDescType {
+	return d | WEAK_DEF
+}
+
+// SetThumbDef returns d with the N_ARM_THUMB_DEF bit set.
+func (d NDescType) SetThumbDef() NDescType {
+	return d | ARM_THUMB_DEF
+}
+
+// SetAltEntry returns d with the N_ALT_ENTRY bit set.
+func (d NDescType) SetAltEntry() NDescType {
+	return d | ALT_ENTRY
+}
+
+// SetColdFunc returns d with the N_COLD_FUNC bit set.
+func (d NDescType) SetColdFunc() NDescType {
+	return d | N_COLD_FUNC
+}
+
+// DescContext supplies the information needed to disambiguate the
+// overloaded 0x0020 and 0x0080 bits of the n_desc field: whether the
+// symbol lives in a relocatable MH_OBJECT file (as opposed to a
+// linked image), and the NType of the symbol the NDescType belongs to.
+type DescContext struct {
+	FileIsObject bool
+	SymType      NType
+}
+
+// String renders the REFERENCE_TYPE bits of t and its flag bits. It
+// has no way to disambiguate the overloaded 0x0020
+// (NO_DEAD_STRIP/DESC_DISCARDED) and 0x0080 (WEAK_DEF/REF_TO_WEAK)
+// bits, so it shows both possible readings for those, and it always
+// reads the high byte as SYMBOL_RESOLVER/ALT_ENTRY/N_COLD_FUNC flags,
+// never as a library ordinal; use StringWithContext when the filetype
+// and symbol NType are known.
 func (t NDescType) String() string {
-	var tStr string
+	return strings.Join(t.flags(nil), "|")
+}
+
+// StringWithContext renders all of t's flags, using context to
+// correctly resolve the overloaded 0x0020 and 0x0080 bits and the
+// library ordinal byte.
+func (t NDescType) StringWithContext(context DescContext) string {
+	return strings.Join(t.flags(&context), "|")
+}
+
+func (t NDescType) flags(ctx *DescContext) []string {
+	var f []string
+
 	if t.IsUndefinedNonLazy() {
-		tStr += "undef_nonlazy|"
+		f = append(f, "undef_nonlazy")
 	}
 	if t.IsUndefinedLazy() {
-		tStr += "undef_lazy|"
+		f = append(f, "undef_lazy")
 	}
 	if t.IsDefined() {
-		tStr += "def|"
+		f = append(f, "def")
 	}
 	if t.IsPrivateDefined() {
-		tStr += "priv_def|"
+		f = append(f, "priv_def")
 	}
 	if t.IsPrivateUndefinedNonLazy() {
-		tStr += "pri_undef_nonlazy|"
+		f = append(f, "pri_undef_nonlazy")
 	}
 	if t.IsPrivateUndefinedLazy() {
-		tStr += "priv_undef_lazy|"
+		f = append(f, "priv_undef_lazy")
 	}
-	// tStr += fmt.Sprintf("libord=%d", t.GetLibraryOrdinal())
-	return strings.TrimSuffix(tStr, "|")
+
+	if t&WEAK_REF != 0 {
+		f = append(f, "weak_ref")
+	}
+
+	if t&0x0020 != 0 {
+		switch {
+		case ctx == nil:
+			f = append(f, "no_dead_strip/discarded")
+		case ctx.FileIsObject:
+			f = append(f, "no_dead_strip")
+		default:
+			f = append(f, "discarded")
+		}
+	}
+
+	if t&0x0080 != 0 {
+		switch {
+		case ctx == nil:
+			f = append(f, "weak_def/ref_to_weak")
+		case ctx.SymType.IsUndefinedSym():
+			f = append(f, "ref_to_weak")
+		default:
+			f = append(f, "weak_def")
+		}
+	}
+
+	if t&ARM_THUMB_DEF != 0 {
+		f = append(f, "thumb_def")
+	}
+
+	// Byte 8-15 (bits covered by SYMBOL_RESOLVER/ALT_ENTRY/N_COLD_FUNC)
+	// is itself overloaded: for an undefined symbol in a linked image
+	// it's the library ordinal GetLibraryOrdinal reads instead.
+	// Render exactly one interpretation, never both.
+	isOrdinal := ctx != nil && !ctx.FileIsObject && ctx.SymType.IsUndefinedSym()
+
+	if !isOrdinal {
+		if t&SYMBOL_RESOLVER != 0 {
+			f = append(f, "resolver")
+		}
+		if t&ALT_ENTRY != 0 {
+			f = append(f, "alt_entry")
+		}
+		if t&N_COLD_FUNC != 0 {
+			f = append(f, "cold_func")
+		}
+	}
+
+	if isOrdinal {
+		if ord := t.GetLibraryOrdinal(); ord != SELF_LIBRARY_ORDINAL {
+			switch ord {
+			case DYNAMIC_LOOKUP_ORDINAL:
+				f = append(f, "libord=dynamic_lookup")
+			case EXECUTABLE_ORDINAL:
+				f = append(f, "libord=executable")
+			default:
+				f = append(f, fmt.Sprintf("libord=%d", uint8(ord)))
+			}
+		}
+	}
+
+	return f
 }
 
 const (
@@ -200,8 +320,6 @@ const (
 	EXECUTABLE_ORDINAL     NDescType = 0xff
 )
 
-// TODO: add these flags to the NDescType String output
-
 const (
 	/*
 	 * The N_NO_DEAD_STRIP bit of the n_desc field only ever appears in a
@@ -0,0 +1,103 @@
+package types
+
+import "testing"
+
+func TestNDescTypeStringNoContext(t *testing.T) {
+	tests := []struct {
+		name string
+		d    NDescType
+		want string
+	}{
+		{"zero value", NDescType(0), "undef_nonlazy"},
+		{"weak ref", NDescType(0).SetWeakRef(), "undef_nonlazy|weak_ref"},
+		{"0x0020 dual reading", NO_DEAD_STRIP, "undef_nonlazy|no_dead_strip/discarded"},
+		{"0x0080 dual reading", WEAK_DEF, "undef_nonlazy|weak_def/ref_to_weak"},
+		{"thumb def", NDescType(0).SetThumbDef(), "undef_nonlazy|thumb_def"},
+		{
+			// With no context, an ordinal byte can't be told apart from
+			// SYMBOL_RESOLVER/ALT_ENTRY/N_COLD_FUNC, so it must render
+			// as those flags, never as libord=N.
+			"ordinal byte without context reads as resolver/cold_func flags",
+			NDescType(0).SetLibraryOrdinal(5),
+			"undef_nonlazy|resolver|cold_func",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.d.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNDescTypeStringWithContextObjectFile(t *testing.T) {
+	ctx := DescContext{FileIsObject: true, SymType: N_UNDF}
+
+	d := NO_DEAD_STRIP | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|no_dead_strip"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Even for an undefined symbol, an MH_OBJECT file never carries a
+	// library ordinal, so the resolver/cold_func bits must still read
+	// as flags.
+	d = NDescType(0).SetLibraryOrdinal(5) | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|resolver|cold_func"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNDescTypeStringWithContextLinkedDefined(t *testing.T) {
+	ctx := DescContext{FileIsObject: false, SymType: N_SECT}
+
+	d := NO_DEAD_STRIP | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|discarded"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	d = WEAK_DEF | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|weak_def"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The symbol is defined, not undefined, so the ordinal byte still
+	// isn't a library ordinal here either.
+	d = NDescType(0).SetLibraryOrdinal(5) | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|resolver|cold_func"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNDescTypeStringWithContextLinkedUndefined(t *testing.T) {
+	ctx := DescContext{FileIsObject: false, SymType: N_UNDF}
+
+	d := NO_DEAD_STRIP | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|discarded"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	d = WEAK_DEF | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|ref_to_weak"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Only here, undefined symbol in a linked image, is the byte
+	// actually a library ordinal rather than resolver/alt_entry/
+	// cold_func flags.
+	d = NDescType(0).SetLibraryOrdinal(5) | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|libord=5"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	d = NDescType(0).SetLibraryOrdinal(DYNAMIC_LOOKUP_ORDINAL) | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|libord=dynamic_lookup"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	d = NDescType(0).SetLibraryOrdinal(EXECUTABLE_ORDINAL) | REFERENCE_FLAG_DEFINED
+	if got, want := d.StringWithContext(ctx), "def|libord=executable"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// SymtabGroups holds the LC_DYSYMTAB index/count pairs that describe
+// how a sorted symbol table is grouped into local, externally-defined
+// and externally-undefined runs.
+type SymtabGroups struct {
+	ILocalSym  uint32
+	NLocalSym  uint32
+	IExtdefSym uint32
+	NExtdefSym uint32
+	IUndefSym  uint32
+	NUndefSym  uint32
+}
+
+// A SymtabBuilder assembles an LC_SYMTAB payload: a symbol list backed
+// by a single deduplicated string pool, ready to be written out as
+// 32- or 64-bit Nlist entries.
+type SymtabBuilder struct {
+	syms    []Nlist64
+	strPool []byte
+	strOff  map[string]uint32
+}
+
+// NewSymtabBuilder returns an empty SymtabBuilder with the required
+// leading NUL already in the string pool.
+func NewSymtabBuilder() *SymtabBuilder {
+	return &SymtabBuilder{
+		strPool: []byte{0},
+		strOff:  map[string]uint32{"": 0},
+	}
+}
+
+// AddSymbol interns name into the string pool (reusing its offset if
+// already present) and appends the symbol, returning its index.
+func (b *SymtabBuilder) AddSymbol(name string, t NType, sect uint8, desc NDescType, value uint64) (index uint32, err error) {
+	b.syms = append(b.syms, Nlist64{
+		Nlist: Nlist{
+			Name: b.intern(name),
+			Type: t,
+			Sect: sect,
+			Desc: desc,
+		},
+		Value: value,
+	})
+	return uint32(len(b.syms) - 1), nil
+}
+
+func (b *SymtabBuilder) intern(name string) uint32 {
+	if off, ok := b.strOff[name]; ok {
+		return off
+	}
+	off := uint32(len(b.strPool))
+	b.strPool = append(b.strPool, []byte(name)...)
+	b.strPool = append(b.strPool, 0)
+	b.strOff[name] = off
+	return off
+}
+
+func (b *SymtabBuilder) nameAt(off uint32) string {
+	end := bytes.IndexByte(b.strPool[off:], 0)
+	return string(b.strPool[off : int(off)+end])
+}
+
+// symRank orders symbols the way Apple's linker requires: local
+// symbols first, then externally-defined symbols, then externally
+// undefined symbols.
+func symRank(n Nlist64) int {
+	if !n.Type.IsExternalSym() {
+		return 0
+	}
+	if n.Type.IsUndefinedSym() {
+		return 2
+	}
+	return 1
+}
+
+// Sort reorders the accumulated symbols into local / extdef / undef
+// order and returns the corresponding LC_DYSYMTAB group counts, plus a
+// map from each symbol's pre-Sort AddSymbol index to its new index so
+// callers who stashed one (e.g. to wire up a relocation's symbol
+// number) can fix up their reference.
+func (b *SymtabBuilder) Sort() (SymtabGroups, map[uint32]uint32) {
+	order := make([]int, len(b.syms))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return symRank(b.syms[order[i]]) < symRank(b.syms[order[j]])
+	})
+
+	sorted := make([]Nlist64, len(b.syms))
+	remap := make(map[uint32]uint32, len(b.syms))
+	for newIdx, oldIdx := range order {
+		sorted[newIdx] = b.syms[oldIdx]
+		remap[uint32(oldIdx)] = uint32(newIdx)
+	}
+	b.syms = sorted
+
+	var g SymtabGroups
+	for _, n := range b.syms {
+		switch symRank(n) {
+		case 0:
+			g.NLocalSym++
+		case 1:
+			g.NExtdefSym++
+		case 2:
+			g.NUndefSym++
+		}
+	}
+	g.ILocalSym = 0
+	g.IExtdefSym = g.NLocalSym
+	g.IUndefSym = g.NLocalSym + g.NExtdefSym
+
+	return g, remap
+}
+
+// MergeFrom appends other's symbols into b, re-interning their names
+// into b's string pool.
+func (b *SymtabBuilder) MergeFrom(other *SymtabBuilder) error {
+	for _, n := range other.syms {
+		if _, err := b.AddSymbol(other.nameAt(n.Name), n.Type, n.Sect, n.Desc, n.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Emit64 serializes the accumulated symbols as Nlist64 entries, ready
+// to be written out as an LC_SYMTAB payload alongside the string pool.
+func (b *SymtabBuilder) Emit64(o binary.ByteOrder) (symtab []byte, strtab []byte) {
+	symtab = make([]byte, len(b.syms)*(8+8))
+	for i, n := range b.syms {
+		n.Put64(symtab[i*(8+8):], o)
+	}
+	return symtab, b.strPool
+}
+
+// Emit32 serializes the accumulated symbols as Nlist32 entries.
+func (b *SymtabBuilder) Emit32(o binary.ByteOrder) (symtab []byte, strtab []byte) {
+	symtab = make([]byte, len(b.syms)*(8+4))
+	for i, n := range b.syms {
+		n32 := Nlist32{Nlist: n.Nlist, Value: uint32(n.Value)}
+		n32.Put32(symtab[i*(8+4):], o)
+	}
+	return symtab, b.strPool
+}
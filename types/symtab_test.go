@@ -0,0 +1,174 @@
+package types
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestSymtabBuilderAddSymbolDedup(t *testing.T) {
+	b := NewSymtabBuilder()
+
+	i1, err := b.AddSymbol("_foo", N_SECT, 1, 0, 0x1000)
+	if err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+	poolLenAfterFirst := len(b.strPool)
+
+	i2, err := b.AddSymbol("_foo", N_SECT|NType(N_EXT), 2, 0, 0x2000)
+	if err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+
+	if i1 == i2 {
+		t.Fatalf("expected distinct symbol indices, got %d for both", i1)
+	}
+	if len(b.strPool) != poolLenAfterFirst {
+		t.Fatalf("string pool grew from %d to %d bytes re-adding the same name", poolLenAfterFirst, len(b.strPool))
+	}
+	if b.syms[i1].Name != b.syms[i2].Name {
+		t.Fatalf("got distinct name offsets %d and %d for the same name, want equal", b.syms[i1].Name, b.syms[i2].Name)
+	}
+	if got, want := b.nameAt(b.syms[i1].Name), "_foo"; got != want {
+		t.Fatalf("nameAt(%d) = %q, want %q", b.syms[i1].Name, got, want)
+	}
+}
+
+func TestSymtabBuilderSort(t *testing.T) {
+	b := NewSymtabBuilder()
+
+	undefIdx, _ := b.AddSymbol("_undef1", NType(N_EXT), 0, 0, 0)
+	local1Idx, _ := b.AddSymbol("_local1", N_SECT, 1, 0, 0x100)
+	defIdx, _ := b.AddSymbol("_def1", N_SECT|NType(N_EXT), 1, 0, 0x200)
+	local2Idx, _ := b.AddSymbol("_local2", N_SECT, 1, 0, 0x300)
+
+	groups, remap := b.Sort()
+
+	wantGroups := SymtabGroups{
+		ILocalSym: 0, NLocalSym: 2,
+		IExtdefSym: 2, NExtdefSym: 1,
+		IUndefSym: 3, NUndefSym: 1,
+	}
+	if groups != wantGroups {
+		t.Fatalf("got groups %+v, want %+v", groups, wantGroups)
+	}
+
+	wantRemap := map[uint32]uint32{
+		local1Idx: 0,
+		local2Idx: 1,
+		defIdx:    2,
+		undefIdx:  3,
+	}
+	for oldIdx, wantNew := range wantRemap {
+		gotNew, ok := remap[oldIdx]
+		if !ok {
+			t.Fatalf("remap missing old index %d", oldIdx)
+		}
+		if gotNew != wantNew {
+			t.Fatalf("remap[%d] = %d, want %d", oldIdx, gotNew, wantNew)
+		}
+	}
+
+	// The symbols must actually have moved to where the remap and
+	// group counts say they did.
+	for oldIdx, newIdx := range wantRemap {
+		wantName := map[uint32]string{
+			local1Idx: "_local1",
+			local2Idx: "_local2",
+			defIdx:    "_def1",
+			undefIdx:  "_undef1",
+		}[oldIdx]
+		if got := b.nameAt(b.syms[newIdx].Name); got != wantName {
+			t.Fatalf("b.syms[%d] = %q, want %q", newIdx, got, wantName)
+		}
+	}
+}
+
+func TestSymtabBuilderEmitRoundTrip(t *testing.T) {
+	b := NewSymtabBuilder()
+	if _, err := b.AddSymbol("_foo", N_SECT, 1, NDescType(0x55), 0x1122334455667788); err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+	if _, err := b.AddSymbol("_bar", N_SECT|NType(N_EXT), 2, NDescType(0xaabb), 0xdeadbeef); err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+
+	o := binary.LittleEndian
+
+	symtab64, strtab64 := b.Emit64(o)
+	if len(symtab64) != 2*16 {
+		t.Fatalf("Emit64 symtab length = %d, want %d", len(symtab64), 2*16)
+	}
+	for i, want := range b.syms {
+		rec := symtab64[i*16:]
+		if got := o.Uint32(rec[0:]); got != want.Name {
+			t.Fatalf("sym %d: name offset = %d, want %d", i, got, want.Name)
+		}
+		if got := NType(rec[4]); got != want.Type {
+			t.Fatalf("sym %d: type = %#x, want %#x", i, got, want.Type)
+		}
+		if got := rec[5]; got != want.Sect {
+			t.Fatalf("sym %d: sect = %d, want %d", i, got, want.Sect)
+		}
+		if got := NDescType(o.Uint16(rec[6:])); got != want.Desc {
+			t.Fatalf("sym %d: desc = %#x, want %#x", i, got, want.Desc)
+		}
+		if got := o.Uint64(rec[8:]); got != want.Value {
+			t.Fatalf("sym %d: value = %#x, want %#x", i, got, want.Value)
+		}
+	}
+	if string(strtab64) != string(b.strPool) {
+		t.Fatalf("Emit64 strtab doesn't match the builder's string pool")
+	}
+
+	symtab32, strtab32 := b.Emit32(o)
+	if len(symtab32) != 2*12 {
+		t.Fatalf("Emit32 symtab length = %d, want %d", len(symtab32), 2*12)
+	}
+	for i, want := range b.syms {
+		rec := symtab32[i*12:]
+		if got := o.Uint32(rec[0:]); got != want.Name {
+			t.Fatalf("sym %d: name offset = %d, want %d", i, got, want.Name)
+		}
+		if got := NType(rec[4]); got != want.Type {
+			t.Fatalf("sym %d: type = %#x, want %#x", i, got, want.Type)
+		}
+		if got := rec[5]; got != want.Sect {
+			t.Fatalf("sym %d: sect = %d, want %d", i, got, want.Sect)
+		}
+		if got := NDescType(o.Uint16(rec[6:])); got != want.Desc {
+			t.Fatalf("sym %d: desc = %#x, want %#x", i, got, want.Desc)
+		}
+		if got := o.Uint32(rec[8:]); got != uint32(want.Value) {
+			t.Fatalf("sym %d: value = %#x, want %#x", i, got, uint32(want.Value))
+		}
+	}
+	if string(strtab32) != string(b.strPool) {
+		t.Fatalf("Emit32 strtab doesn't match the builder's string pool")
+	}
+}
+
+func TestSymtabBuilderMergeFrom(t *testing.T) {
+	a := NewSymtabBuilder()
+	if _, err := a.AddSymbol("_a", N_SECT, 1, 0, 0x10); err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+
+	b := NewSymtabBuilder()
+	if _, err := b.AddSymbol("_b", N_SECT, 1, 0, 0x20); err != nil {
+		t.Fatalf("AddSymbol: %v", err)
+	}
+
+	if err := a.MergeFrom(b); err != nil {
+		t.Fatalf("MergeFrom: %v", err)
+	}
+
+	if len(a.syms) != 2 {
+		t.Fatalf("got %d symbols after merge, want 2", len(a.syms))
+	}
+	if got := a.nameAt(a.syms[1].Name); got != "_b" {
+		t.Fatalf("merged symbol name = %q, want _b", got)
+	}
+	if got := a.syms[1].Value; got != 0x20 {
+		t.Fatalf("merged symbol value = %#x, want 0x20", got)
+	}
+}